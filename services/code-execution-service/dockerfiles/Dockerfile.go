@@ -1,7 +1,16 @@
 # ============================================
 # Go Optimized Dockerfile (Multi-stage)
 # ============================================
-FROM golang:1.21-alpine AS go-builder
+# GO_BUILDER_IMAGE/GO_EXECUTOR_IMAGE are resolved per-submission by
+# docker_manager.py from languages/go/versions.yaml, which pins them to a
+# digest. The defaults here exist only so this Dockerfile can still be
+# built standalone for local debugging.
+ARG GO_BUILDER_IMAGE=golang:1.21-alpine
+ARG GO_EXECUTOR_IMAGE=alpine:3.18
+
+FROM ${GO_BUILDER_IMAGE} AS go-builder
+
+ARG TARGETARCH
 
 WORKDIR /app
 
@@ -10,14 +19,75 @@ RUN apk add --no-cache \
     git \
     ca-certificates
 
-# This stage is used for compilation only
-# The actual compilation happens at runtime via docker_manager.py
+# The submission's main.go is passed in as a build context file so that
+# BuildKit can cache module downloads and compiled packages across
+# submissions instead of recompiling the stdlib from scratch every time.
+COPY main.go .
+
+# CGO_ENABLED=0 forces a fully static binary: if left enabled, a
+# submission that transitively pulls in a cgo dependency would link
+# against the builder's musl libc and then fail (or segfault) once
+# copied into the executor stage's separate filesystem.
+# go mod tidy resolves and records every third-party import the
+# submission makes (with GOFLAGS=-mod=mod so it's allowed to write
+# go.mod/go.sum); without it, -mod=readonly's default leaves the module
+# graph empty and any submission importing a non-stdlib package fails
+# to build with "no required module provides package".
+RUN --mount=type=cache,target=/go/pkg/mod \
+    --mount=type=cache,target=/root/.cache/go-build \
+    go mod init solution 2>/dev/null; \
+    GOFLAGS=-mod=mod go mod tidy; \
+    CGO_ENABLED=0 GOOS=linux GOARCH=${TARGETARCH} \
+    go build -ldflags="-s -w" -trimpath -o /out/solution main.go
+
+# A CycloneDX SBOM of the compiled module graph is kept alongside the
+# submission record for dependency auditing and reproducibility; see
+# the sbom package for how docker_manager.py retrieves /out/sbom.json.
+RUN --mount=type=cache,target=/go/pkg/mod \
+    --mount=type=cache,target=/root/.cache/go-build \
+    go install github.com/CycloneDX/cyclonedx-gomod/cmd/cyclonedx-gomod@v1.4.1 && \
+    cyclonedx-gomod app -json -output /out/sbom.json .
+
+# A compiled solution can easily top 5-10MB of stdlib, which slows the
+# image transfer into each ephemeral executor container. This stage
+# shrinks it with UPX; it's a separate stage (rather than a RUN in
+# go-builder) so a failed/disabled compression never has to rebuild Go.
+FROM alpine:3.18 AS go-compressor
 
-FROM alpine:3.18 AS go-executor
+ARG TARGETARCH
+ARG COMPRESS=1
+ARG UPX_VERSION=4.2.2
+ARG UPX_ARGS="--best --lzma"
 
-# Install minimal runtime dependencies
+WORKDIR /out
+COPY --from=go-builder /out/solution /out/solution
+
+# Some Go binaries (certain GC/runtime features) don't survive upx
+# --brute, so a failed compression falls back to shipping the
+# uncompressed binary instead of breaking the build. The upx release
+# asset name is arch-specific (amd64_linux/arm64_linux) and must match
+# TARGETARCH, or the binary silently no-ops with "exec format error"
+# swallowed by the fallback below.
+RUN set -e; \
+    if [ "$COMPRESS" = "1" ]; then \
+        apk add --no-cache curl xz; \
+        curl -fsSL -o /tmp/upx.tar.xz \
+            "https://github.com/upx/upx/releases/download/v${UPX_VERSION}/upx-${UPX_VERSION}-${TARGETARCH}_linux.tar.xz"; \
+        tar -xJf /tmp/upx.tar.xz -C /tmp; \
+        install -m 0755 "/tmp/upx-${UPX_VERSION}-${TARGETARCH}_linux/upx" /usr/local/bin/upx; \
+        ORIG_SIZE=$(stat -c%s /out/solution); \
+        upx ${UPX_ARGS} /out/solution || echo "upx failed, shipping uncompressed solution"; \
+        NEW_SIZE=$(stat -c%s /out/solution); \
+        echo "solution size: ${ORIG_SIZE} -> ${NEW_SIZE} bytes"; \
+    fi
+
+FROM ${GO_EXECUTOR_IMAGE} AS go-executor
+
+# `file` backs the ABI probe in go-entrypoint.sh that rejects a
+# dynamically-linked solution before it can crash at exec time.
 RUN apk add --no-cache \
     ca-certificates \
+    file \
     && rm -rf /var/cache/apk/*
 
 WORKDIR /app
@@ -26,8 +96,18 @@ WORKDIR /app
 RUN adduser -D -u 1000 coderunner \
     && chown coderunner:coderunner /app
 
+COPY --from=go-compressor /out/solution /app/solution
+# Carried into the executor image (rather than left in the discarded
+# go-builder stage) so docker_manager.py can pull it straight out of the
+# image it already built via `docker run --entrypoint cat`, instead of
+# re-running a second build just to get the SBOM.
+COPY --from=go-builder /out/sbom.json /app/sbom.json
+COPY go-entrypoint.sh /app/go-entrypoint.sh
+RUN chown coderunner:coderunner /app/solution /app/sbom.json /app/go-entrypoint.sh \
+    && chmod +x /app/go-entrypoint.sh
+
 # Switch to non-root user
 USER coderunner
 
 # Default command to execute Go binary
-CMD ["/app/solution"]
\ No newline at end of file
+CMD ["/app/go-entrypoint.sh"]